@@ -15,6 +15,7 @@
 package tracegen
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"sync"
@@ -23,19 +24,34 @@ import (
 
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
 )
 
 // Config describes the test scenario.
 type Config struct {
-	Workers       int
-	Traces        int
-	Marshal       bool
-	Debug         bool
-	Firehose      bool
-	Pause         time.Duration
-	Duration      time.Duration
-	Service       string
-	TraceExporter string
+	Workers           int
+	Traces            int
+	Marshal           bool
+	Debug             bool
+	Firehose          bool
+	Pause             time.Duration
+	Duration          time.Duration
+	Service           string
+	TraceExporter     string
+	Topology          string
+	Rate              float64
+	RampUp            time.Duration
+	MaxInflight       int
+	MetricsListenAddr string
+	WaitForReady      bool
+	ConnectTimeout    time.Duration
+	Replay            string
+	ReplaySpeed       float64
+	ReplayLoop        bool
+	Scenario          string
+	ScenarioDepth     int
+	ScenarioFanout    int
 }
 
 // Flags registers config flags.
@@ -48,32 +64,127 @@ func (c *Config) Flags(fs *flag.FlagSet) {
 	fs.DurationVar(&c.Duration, "duration", 0, "For how long to run the test")
 	fs.StringVar(&c.Service, "service", "tracegen", "Service name to use")
 	fs.StringVar(&c.TraceExporter, "trace-exporter", "jaeger", "Trace exporter (jaeger|otlp-grpc|otlp-http|stdout). Exporters can be additionally configured via environment variables, see https://github.com/jaegertracing/jaeger/blob/main/cmd/tracegen/README.md")
+	fs.StringVar(&c.Topology, "topology", "", "Path to a YAML or JSON file describing a service graph to generate multi-service traces from. When unset, tracegen emits its default single-service root+child trace.")
+	fs.Float64Var(&c.Rate, "rate", 0, "Target number of traces per second to emit. When 0 (the default), tracegen emits as fast as possible, limited only by `workers`/`pause`.")
+	fs.DurationVar(&c.RampUp, "ramp-up", 0, "Duration over which to linearly ramp up from 1/20 of the target `rate` to the full target `rate`, instead of starting at full rate immediately")
+	fs.IntVar(&c.MaxInflight, "max-inflight", 0, "Maximum number of traces being emitted concurrently across all workers. When 0 (the default), there is no limit. Traces that would exceed the limit are dropped and counted rather than queued.")
+	fs.StringVar(&c.MetricsListenAddr, "metrics-listen-addr", "", "If set, serve Prometheus metrics (submission latency histogram, success/failure/dropped counters) on this address, e.g. :8888")
+	fs.BoolVar(&c.WaitForReady, "wait-for-ready", false, "Block until the gRPC exporter connection (otlp-grpc/jaeger) reaches the READY state before emitting any traces, instead of proceeding while the collector may be unreachable")
+	fs.DurationVar(&c.ConnectTimeout, "connect-timeout", 10*time.Second, "How long `-wait-for-ready` waits for the gRPC exporter connection to become ready before failing")
+	fs.StringVar(&c.Replay, "replay", "", "Path to a file of previously captured spans (OTLP JSON, Jaeger JSON from /api/traces, or Zipkin JSON) to re-emit through the configured exporter, instead of generating synthetic traces. Overrides `-workers`/`-traces`/`-topology`.")
+	fs.Float64Var(&c.ReplaySpeed, "replay-speed", 1, "Scales replay timing relative to how the spans were originally captured; 2 replays twice as fast, 0.5 replays at half speed")
+	fs.BoolVar(&c.ReplayLoop, "replay-loop", false, "Replay the file repeatedly until stopped, generating fresh trace/span IDs each time so replays don't collide")
+	fs.StringVar(&c.Scenario, "scenario", "default", "Comma-separated, optionally weighted list of span-shape scenarios to emit, e.g. `deep_tree:3,wide_fanout:1,error_cascade:1,span_links:1,long_running:1`. Ignored when `-topology` is set.")
+	fs.IntVar(&c.ScenarioDepth, "scenario-depth", 5, "Chain depth used by the deep_tree and error_cascade scenarios")
+	fs.IntVar(&c.ScenarioFanout, "scenario-fanout", 5, "Number of concurrent children used by the wide_fanout scenario")
 }
 
 // Run executes the test scenario.
-func Run(c *Config, tracer trace.Tracer, logger *zap.Logger) error {
+//
+// newTracerProvider builds the trace.TracerProvider used to obtain a
+// trace.Tracer for a given service name. Run calls it once per distinct
+// service name it needs to emit spans for: c.Service always, plus once per
+// Topology.Services entry visited when a topology is configured. Each
+// call must return a provider whose Resource identifies that service (e.g.
+// via semconv.ServiceName(service)), since it's the Resource — not the
+// tracer/instrumentation-scope name — that Jaeger/OTLP exporters use to
+// assign a span's service identity; a topology's whole point is to produce
+// traces that span distinct services, so reusing one provider/Resource for
+// every service would collapse them all into one.
+//
+// conn is the gRPC connection underlying the configured exporter, when
+// TraceExporter is "otlp-grpc" or "jaeger"; it is used to log connectivity
+// state transitions and, if WaitForReady is set, to block Run until the
+// connection is ready. Pass nil for non-gRPC exporters.
+func Run(c *Config, newTracerProvider func(service string) trace.TracerProvider, conn *grpc.ClientConn, logger *zap.Logger) error {
+	if c.Replay != "" {
+		return runReplay(c, newTracerProvider(c.Service), logger)
+	}
+
 	if c.Duration > 0 {
 		c.Traces = 0
 	} else if c.Traces <= 0 {
 		return fmt.Errorf("either `traces` or `duration` must be greater than 0")
 	}
 
+	m := newMetrics()
+	if c.MetricsListenAddr != "" {
+		if err := m.serve(c.MetricsListenAddr); err != nil {
+			return err
+		}
+		defer m.close(context.Background())
+	}
+	defer m.logSummary(logger)
+
+	if conn != nil {
+		watchCtx, cancelWatch := context.WithCancel(context.Background())
+		defer cancelWatch()
+		go watchConnState(watchCtx, conn, logger, m)
+
+		if c.WaitForReady {
+			if err := waitForReady(context.Background(), conn, c.ConnectTimeout); err != nil {
+				return fmt.Errorf("exporter connection never became ready: %w", err)
+			}
+		}
+	}
+
+	var topology *Topology
+	if c.Topology != "" {
+		t, err := LoadTopology(c.Topology)
+		if err != nil {
+			return fmt.Errorf("failed to load topology: %w", err)
+		}
+		topology = t
+	}
+	tracerFor := newCachingTracerFor(newTracerProvider)
+
+	var scenarios *scenarioPicker
+	if topology == nil {
+		p, err := newScenarioPicker(c.Scenario, c)
+		if err != nil {
+			return fmt.Errorf("failed to parse scenario spec: %w", err)
+		}
+		scenarios = p
+	}
+
+	var limiter *rate.Limiter
+	if c.Rate > 0 {
+		initialRate := c.Rate
+		if c.RampUp > 0 {
+			initialRate = c.Rate / rampUpSteps
+		}
+		limiter = rate.NewLimiter(rate.Limit(initialRate), 1)
+		if c.RampUp > 0 {
+			go rampUpRate(limiter, c.Rate, c.RampUp)
+		}
+	}
+	var inflight chan struct{}
+	if c.MaxInflight > 0 {
+		inflight = make(chan struct{}, c.MaxInflight)
+	}
+
 	wg := sync.WaitGroup{}
 	var running uint32 = 1
 	for i := 0; i < c.Workers; i++ {
 		wg.Add(1)
 		w := worker{
-			id:       i,
-			tracer:   tracer,
-			traces:   c.Traces,
-			marshal:  c.Marshal,
-			debug:    c.Debug,
-			firehose: c.Firehose,
-			pause:    c.Pause,
-			duration: c.Duration,
-			running:  &running,
-			wg:       &wg,
-			logger:   logger.With(zap.Int("worker", i)),
+			id:        i,
+			tracer:    tracerFor(c.Service),
+			tracerFor: tracerFor,
+			topology:  topology,
+			scenarios: scenarios,
+			traces:    c.Traces,
+			marshal:   c.Marshal,
+			debug:     c.Debug,
+			firehose:  c.Firehose,
+			pause:     c.Pause,
+			duration:  c.Duration,
+			running:   &running,
+			wg:        &wg,
+			logger:    logger.With(zap.Int("worker", i)),
+			limiter:   limiter,
+			inflight:  inflight,
+			metrics:   m,
 		}
 
 		go w.simulateTraces()
@@ -85,3 +196,46 @@ func Run(c *Config, tracer trace.Tracer, logger *zap.Logger) error {
 	wg.Wait()
 	return nil
 }
+
+// newCachingTracerFor wraps newTracerProvider so each distinct service name
+// gets exactly one underlying trace.TracerProvider, built lazily the first
+// time a worker needs it and reused for every span emitted for that
+// service afterwards, rather than a fresh (and differently-resourced)
+// provider per call. The returned func is safe for concurrent use by
+// multiple workers.
+func newCachingTracerFor(newTracerProvider func(service string) trace.TracerProvider) func(service string) trace.Tracer {
+	var mu sync.Mutex
+	tracers := make(map[string]trace.Tracer)
+	return func(service string) trace.Tracer {
+		mu.Lock()
+		defer mu.Unlock()
+		if t, ok := tracers[service]; ok {
+			return t
+		}
+		t := newTracerProvider(service).Tracer(service)
+		tracers[service] = t
+		return t
+	}
+}
+
+// rampUpSteps is the number of discrete increments rampUpRate uses to climb
+// from its starting rate to the target rate. The limiter is initialized at
+// target/rampUpSteps before this goroutine starts, so the first step keeps
+// it there rather than momentarily running at the full target rate.
+const rampUpSteps = 20
+
+// rampUpRate linearly increases limiter's rate from a near-zero starting
+// point to target over the given duration, in small steps, so a load test
+// doesn't slam the collector with the full target rate from the first
+// tick.
+func rampUpRate(limiter *rate.Limiter, target float64, rampUp time.Duration) {
+	step := rampUp / rampUpSteps
+	if step <= 0 {
+		limiter.SetLimit(rate.Limit(target))
+		return
+	}
+	for i := 1; i <= rampUpSteps; i++ {
+		time.Sleep(step)
+		limiter.SetLimit(rate.Limit(target * float64(i) / rampUpSteps))
+	}
+}
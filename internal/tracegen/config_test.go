@@ -0,0 +1,44 @@
+// Copyright (c) 2018 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracegen
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestRampUpRateClimbsFromLowToTarget(t *testing.T) {
+	limiter := rate.NewLimiter(rate.Limit(100.0/rampUpSteps), 1)
+	start := time.Now()
+	rampUpRate(limiter, 100, 20*time.Millisecond)
+	elapsed := time.Since(start)
+
+	if elapsed < 20*time.Millisecond {
+		t.Fatalf("rampUpRate returned after %v, want at least the full ramp-up duration", elapsed)
+	}
+	if got := float64(limiter.Limit()); got != 100 {
+		t.Fatalf("limiter.Limit() after ramp-up = %v, want 100 (target)", got)
+	}
+}
+
+func TestRampUpRateZeroDurationSetsTargetImmediately(t *testing.T) {
+	limiter := rate.NewLimiter(rate.Limit(100.0/rampUpSteps), 1)
+	rampUpRate(limiter, 100, 0)
+	if got := float64(limiter.Limit()); got != 100 {
+		t.Fatalf("limiter.Limit() with zero rampUp = %v, want 100 (target set immediately)", got)
+	}
+}
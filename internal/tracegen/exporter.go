@@ -0,0 +1,74 @@
+// Copyright (c) 2024 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracegen
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/connectivity"
+)
+
+// grpcConn is the subset of *grpc.ClientConn that tracegen needs to
+// observe connection lifecycle. It exists so tests can supply a fake
+// without spinning up a real dial.
+type grpcConn interface {
+	GetState() connectivity.State
+	WaitForStateChange(ctx context.Context, sourceState connectivity.State) bool
+}
+
+// watchConnState runs until ctx is cancelled, logging every gRPC
+// connection state transition (IDLE -> CONNECTING -> READY ->
+// TRANSIENT_FAILURE -> SHUTDOWN, in whatever order the connection takes
+// them) and recording them in m. It is meant to be run in its own
+// goroutine.
+func watchConnState(ctx context.Context, conn grpcConn, logger *zap.Logger, m *metrics) {
+	state := conn.GetState()
+	logger.Info("gRPC connection state", zap.String("state", state.String()))
+	if m != nil {
+		m.observeConnState(state)
+	}
+	for conn.WaitForStateChange(ctx, state) {
+		state = conn.GetState()
+		logger.Info("gRPC connection state changed", zap.String("state", state.String()))
+		if m != nil {
+			m.observeConnState(state)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// waitForReady blocks until conn reaches connectivity.Ready, or returns an
+// error once timeout elapses or the connection reaches Shutdown.
+func waitForReady(ctx context.Context, conn grpcConn, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	state := conn.GetState()
+	for state != connectivity.Ready {
+		if state == connectivity.Shutdown {
+			return fmt.Errorf("gRPC connection shut down before becoming ready")
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("timed out after %s waiting for gRPC connection to become ready (last state: %s)", timeout, state)
+		}
+		state = conn.GetState()
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2024 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracegen
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/connectivity"
+)
+
+// fakeConn implements grpcConn, replaying a fixed sequence of states one
+// WaitForStateChange call at a time.
+type fakeConn struct {
+	states []connectivity.State
+	idx    int
+}
+
+func (f *fakeConn) GetState() connectivity.State {
+	return f.states[f.idx]
+}
+
+func (f *fakeConn) WaitForStateChange(ctx context.Context, sourceState connectivity.State) bool {
+	if f.idx+1 >= len(f.states) {
+		<-ctx.Done()
+		return false
+	}
+	f.idx++
+	return true
+}
+
+func TestWaitForReadySucceedsOnceReady(t *testing.T) {
+	conn := &fakeConn{states: []connectivity.State{connectivity.Connecting, connectivity.Ready}}
+	if err := waitForReady(context.Background(), conn, time.Second); err != nil {
+		t.Fatalf("waitForReady() = %v, want nil", err)
+	}
+}
+
+func TestWaitForReadyErrorsOnShutdown(t *testing.T) {
+	conn := &fakeConn{states: []connectivity.State{connectivity.Connecting, connectivity.Shutdown}}
+	err := waitForReady(context.Background(), conn, time.Second)
+	if err == nil {
+		t.Fatal("waitForReady() = nil, want error on shutdown")
+	}
+}
+
+func TestWaitForReadyTimesOut(t *testing.T) {
+	conn := &fakeConn{states: []connectivity.State{connectivity.Connecting}}
+	err := waitForReady(context.Background(), conn, 10*time.Millisecond)
+	if err == nil {
+		t.Fatal("waitForReady() = nil, want timeout error")
+	}
+}
+
+func TestWatchConnStateRecordsEachTransition(t *testing.T) {
+	conn := &fakeConn{states: []connectivity.State{connectivity.Connecting, connectivity.Ready, connectivity.TransientFailure}}
+	m := newMetrics()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		watchConnState(ctx, conn, zap.NewNop(), m)
+		close(done)
+	}()
+
+	// Give watchConnState time to drain every state in the fake connection's
+	// sequence before it blocks waiting on the next (nonexistent) change.
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watchConnState did not return after ctx was cancelled")
+	}
+
+	for _, state := range []connectivity.State{connectivity.Connecting, connectivity.Ready, connectivity.TransientFailure} {
+		got := testutilCounterValue(t, m, state.String())
+		if got != 1 {
+			t.Fatalf("connStateChanges[%s] = %v, want 1", state, got)
+		}
+	}
+}
+
+func testutilCounterValue(t *testing.T, m *metrics, state string) float64 {
+	t.Helper()
+	c, err := m.connStateChanges.GetMetricWithLabelValues(state)
+	if err != nil {
+		t.Fatalf("GetMetricWithLabelValues(%q): %v", state, err)
+	}
+	var out dto.Metric
+	if err := c.Write(&out); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	return out.GetCounter().GetValue()
+}
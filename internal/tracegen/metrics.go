@@ -0,0 +1,152 @@
+// Copyright (c) 2024 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracegen
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/connectivity"
+)
+
+// metrics tracks submission latency and outcome counters for the traces
+// tracegen emits, so an operator running a rate-limited load test can tell
+// whether the collector kept up.
+//
+// "Submission latency" here is the wall-clock time from starting a trace's
+// root span to ending its last span, which is a proxy for exporter
+// responsiveness: tracegen has no visibility into the batch span
+// processor's actual network round trip.
+type metrics struct {
+	mu        sync.Mutex
+	histogram *hdrhistogram.Histogram
+
+	succeeded atomic.Int64
+	failed    atomic.Int64
+	dropped   atomic.Int64
+
+	latency          prometheus.Histogram
+	connStateChanges *prometheus.CounterVec
+	registry         *prometheus.Registry
+	server           *http.Server
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		// 1 microsecond to 10 minutes, 3 significant figures.
+		histogram: hdrhistogram.New(1, (10 * time.Minute).Microseconds(), 3),
+		latency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "tracegen_submission_latency_seconds",
+			Help:    "End-to-end latency of emitting a single trace, from root span start to last span end.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		connStateChanges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "tracegen_grpc_conn_state_changes_total",
+			Help: "Number of times the gRPC exporter connection entered a given connectivity state.",
+		}, []string{"state"}),
+		registry: prometheus.NewRegistry(),
+	}
+	m.registry.MustRegister(
+		m.latency,
+		m.connStateChanges,
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "tracegen_traces_succeeded_total",
+			Help: "Number of traces successfully submitted to the exporter.",
+		}, func() float64 { return float64(m.succeeded.Load()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "tracegen_traces_failed_total",
+			Help: "Number of traces that failed submission to the exporter.",
+		}, func() float64 { return float64(m.failed.Load()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "tracegen_spans_dropped_total",
+			Help: "Number of traces skipped without being emitted because the max-inflight limit was reached.",
+		}, func() float64 { return float64(m.dropped.Load()) }),
+	)
+	return m
+}
+
+// observe records the outcome of a single trace submission.
+func (m *metrics) observe(d time.Duration, err error) {
+	m.mu.Lock()
+	_ = m.histogram.RecordValue(d.Microseconds())
+	m.mu.Unlock()
+
+	m.latency.Observe(d.Seconds())
+	if err != nil {
+		m.failed.Add(1)
+	} else {
+		m.succeeded.Add(1)
+	}
+}
+
+// incDropped records one trace that a worker skipped, emitting none of its
+// spans, because the inflight limit was reached.
+func (m *metrics) incDropped() {
+	m.dropped.Add(1)
+}
+
+// observeConnState records a gRPC exporter connection entering state.
+func (m *metrics) observeConnState(state connectivity.State) {
+	m.connStateChanges.WithLabelValues(state.String()).Inc()
+}
+
+// serve starts an HTTP server exposing the metrics at /metrics in
+// Prometheus exposition format. Callers must call close when done.
+func (m *metrics) serve(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on metrics address %q: %w", addr, err)
+	}
+	m.server = &http.Server{Handler: mux}
+	go func() {
+		_ = m.server.Serve(ln)
+	}()
+	return nil
+}
+
+func (m *metrics) close(ctx context.Context) {
+	if m.server != nil {
+		_ = m.server.Shutdown(ctx)
+	}
+}
+
+// logSummary logs a human-readable report of the collected histogram and
+// counters, printed at shutdown.
+func (m *metrics) logSummary(logger *zap.Logger) {
+	m.mu.Lock()
+	h := m.histogram
+	m.mu.Unlock()
+
+	logger.Info("tracegen run summary",
+		zap.Int64("traces_succeeded", m.succeeded.Load()),
+		zap.Int64("traces_failed", m.failed.Load()),
+		zap.Int64("spans_dropped", m.dropped.Load()),
+		zap.Int64("latency_p50_us", h.ValueAtQuantile(50)),
+		zap.Int64("latency_p95_us", h.ValueAtQuantile(95)),
+		zap.Int64("latency_p99_us", h.ValueAtQuantile(99)),
+		zap.Int64("latency_max_us", h.Max()),
+	)
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2024 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracegen
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMetricsObserveTracksSuccessAndFailure(t *testing.T) {
+	m := newMetrics()
+
+	m.observe(time.Millisecond, nil)
+	m.observe(time.Millisecond, nil)
+	m.observe(time.Millisecond, errors.New("submission failed"))
+
+	if got := m.succeeded.Load(); got != 2 {
+		t.Fatalf("succeeded = %d, want 2", got)
+	}
+	if got := m.failed.Load(); got != 1 {
+		t.Fatalf("failed = %d, want 1", got)
+	}
+}
+
+func TestMetricsIncDropped(t *testing.T) {
+	m := newMetrics()
+	m.incDropped()
+	m.incDropped()
+	if got := m.dropped.Load(); got != 2 {
+		t.Fatalf("dropped = %d, want 2", got)
+	}
+}
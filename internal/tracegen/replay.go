@@ -0,0 +1,264 @@
+// Copyright (c) 2024 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracegen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// ReplayConfig describes a replay run: reading previously captured spans
+// from a file and re-emitting them through the configured exporter instead
+// of generating synthetic traces.
+type ReplayConfig struct {
+	// File is a path to a JSON file containing captured spans, in OTLP
+	// JSON, Jaeger JSON (as returned by GET /api/traces/{id}), or Zipkin
+	// JSON format. The format is detected automatically.
+	File string
+	// Speed scales the replay relative to how the spans were originally
+	// captured: 2 replays twice as fast, 0.5 replays at half speed. A
+	// value <= 0 is treated as 1 (real-time).
+	Speed float64
+	// Loop replays the file repeatedly until the process is stopped. Each
+	// iteration gets fresh trace/span IDs so traces don't collide.
+	Loop bool
+}
+
+// capturedSpan is tracegen's unified in-memory representation of a single
+// span read from a captured trace file, regardless of its source format.
+type capturedSpan struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Name         string
+	Kind         trace.SpanKind
+	StartTime    time.Time
+	EndTime      time.Time
+	Attributes   []attribute.KeyValue
+	Events       []capturedEvent
+	Links        []capturedLink
+	StatusCode   codes.Code
+	StatusMsg    string
+
+	children []*capturedSpan
+}
+
+type capturedEvent struct {
+	Name       string
+	Time       time.Time
+	Attributes []attribute.KeyValue
+}
+
+type capturedLink struct {
+	TraceID string
+	SpanID  string
+}
+
+// loadCapturedTraces reads path and groups its spans into traces, keyed by
+// their original trace ID, with each trace's spans linked into a tree
+// rooted at its span(s) with no parent, or whose parent is not in the file.
+func loadCapturedTraces(path string) (map[string][]*capturedSpan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replay file: %w", err)
+	}
+
+	spans, err := parseCapturedSpans(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keyed by (TraceID, SpanID), not bare SpanID: low-entropy span IDs
+	// (e.g. from Zipkin/test-tool captures) can collide across unrelated
+	// traces in the same file, and a bare-SpanID lookup would then
+	// silently attach a span to the wrong trace as someone else's child.
+	type spanKey struct{ traceID, spanID string }
+	byID := make(map[spanKey]*capturedSpan, len(spans))
+	for _, s := range spans {
+		byID[spanKey{s.TraceID, s.SpanID}] = s
+	}
+	traces := make(map[string][]*capturedSpan)
+	for _, s := range spans {
+		if parent, ok := byID[spanKey{s.TraceID, s.ParentSpanID}]; ok && s.ParentSpanID != "" {
+			parent.children = append(parent.children, s)
+			continue
+		}
+		traces[s.TraceID] = append(traces[s.TraceID], s)
+	}
+	for _, roots := range traces {
+		sort.Slice(roots, func(i, j int) bool { return roots[i].StartTime.Before(roots[j].StartTime) })
+	}
+	return traces, nil
+}
+
+// parseCapturedSpans detects the file's format and decodes it into a flat
+// list of capturedSpan.
+func parseCapturedSpans(data []byte) ([]*capturedSpan, error) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err == nil {
+		if _, ok := probe["resourceSpans"]; ok {
+			return parseOTLPJSON(data)
+		}
+		if _, ok := probe["data"]; ok {
+			return parseJaegerJSON(data)
+		}
+	}
+	// Zipkin captures are a bare JSON array of spans.
+	var arrayProbe []json.RawMessage
+	if err := json.Unmarshal(data, &arrayProbe); err == nil {
+		return parseZipkinJSON(data)
+	}
+	return nil, fmt.Errorf("replay file is not recognized OTLP, Jaeger, or Zipkin JSON")
+}
+
+// replayTraces replays every trace in traces through tracer, scaling
+// inter-span timing by 1/speed, optionally looping until ctx is cancelled.
+func replayTraces(ctx context.Context, tracer trace.Tracer, traces map[string][]*capturedSpan, speed float64, loop bool, logger *zap.Logger) {
+	if speed <= 0 {
+		speed = 1
+	}
+	for {
+		for traceID, roots := range traces {
+			var wg sync.WaitGroup
+			base := time.Now()
+			origin := earliestStart(roots)
+			for _, root := range roots {
+				wg.Add(1)
+				go func(root *capturedSpan) {
+					defer wg.Done()
+					replaySpan(ctx, tracer, root, base, origin, speed)
+				}(root)
+			}
+			wg.Wait()
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Debug("replayed trace", zap.String("trace_id", traceID))
+		}
+		if !loop || ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func earliestStart(roots []*capturedSpan) time.Time {
+	earliest := roots[0].StartTime
+	for _, r := range roots[1:] {
+		if r.StartTime.Before(earliest) {
+			earliest = r.StartTime
+		}
+	}
+	return earliest
+}
+
+// waitUntil blocks until t, or returns early (reporting false) if ctx is
+// cancelled first. This is what lets -duration actually bound a replay run:
+// a captured trace can have inter-span gaps far longer than the remaining
+// run budget, and a plain time.Sleep would sleep straight through ctx's
+// deadline regardless.
+func waitUntil(ctx context.Context, t time.Time) bool {
+	d := time.Until(t)
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	select {
+	case <-time.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// replaySpan emits span and recursively its children, preserving their
+// original relative timing (scaled by speed) against base, the wall-clock
+// time this replay iteration started. It stops without emitting once ctx
+// is cancelled.
+func replaySpan(ctx context.Context, tracer trace.Tracer, span *capturedSpan, base, origin time.Time, speed float64) {
+	scaledOffset := time.Duration(float64(span.StartTime.Sub(origin)) / speed)
+	startAt := base.Add(scaledOffset)
+	if !waitUntil(ctx, startAt) {
+		return
+	}
+
+	spanCtx, sp := tracer.Start(ctx, span.Name,
+		trace.WithTimestamp(startAt),
+		trace.WithSpanKind(span.Kind),
+		trace.WithAttributes(span.Attributes...),
+	)
+	// Captured links reference span contexts from the original capture,
+	// not this replay run, so they can't be attached as real trace.Link
+	// values; record them as attributes instead so they remain visible.
+	for _, link := range span.Links {
+		sp.SetAttributes(attribute.String("replay.link.trace_id", link.TraceID), attribute.String("replay.link.span_id", link.SpanID))
+	}
+	for _, ev := range span.Events {
+		evAt := base.Add(time.Duration(float64(ev.Time.Sub(origin)) / speed))
+		sp.AddEvent(ev.Name, trace.WithTimestamp(evAt), trace.WithAttributes(ev.Attributes...))
+	}
+	if span.StatusCode != codes.Unset {
+		sp.SetStatus(span.StatusCode, span.StatusMsg)
+	}
+
+	children := append([]*capturedSpan(nil), span.children...)
+	sort.Slice(children, func(i, j int) bool { return children[i].StartTime.Before(children[j].StartTime) })
+	var wg sync.WaitGroup
+	for _, child := range children {
+		wg.Add(1)
+		go func(child *capturedSpan) {
+			defer wg.Done()
+			replaySpan(spanCtx, tracer, child, base, origin, speed)
+		}(child)
+	}
+	wg.Wait()
+
+	endAt := base.Add(time.Duration(float64(span.EndTime.Sub(origin)) / speed))
+	waitUntil(ctx, endAt)
+	sp.End(trace.WithTimestamp(endAt))
+}
+
+// runReplay implements Config.Replay: it loads the captured traces and
+// re-emits them through a tracer obtained from tp, once or in a loop,
+// instead of running the regular worker-based generation.
+func runReplay(c *Config, tp trace.TracerProvider, logger *zap.Logger) error {
+	rc := ReplayConfig{File: c.Replay, Speed: c.ReplaySpeed, Loop: c.ReplayLoop}
+
+	traces, err := loadCapturedTraces(rc.File)
+	if err != nil {
+		return fmt.Errorf("failed to load replay file: %w", err)
+	}
+	if len(traces) == 0 {
+		return fmt.Errorf("replay file %q contains no spans", rc.File)
+	}
+	logger.Info("replaying captured traces", zap.String("file", rc.File), zap.Int("traces", len(traces)), zap.Float64("speed", rc.Speed), zap.Bool("loop", rc.Loop))
+
+	ctx := context.Background()
+	var cancel context.CancelFunc
+	if c.Duration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.Duration)
+		defer cancel()
+	}
+	replayTraces(ctx, tp.Tracer(c.Service), traces, rc.Speed, rc.Loop, logger)
+	return nil
+}
@@ -0,0 +1,356 @@
+// Copyright (c) 2024 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracegen
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// --- OTLP JSON -------------------------------------------------------------
+
+type otlpExportRequest struct {
+	ResourceSpans []struct {
+		ScopeSpans []struct {
+			Spans []otlpSpan `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"resourceSpans"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue  `json:"attributes"`
+	Events            []otlpEvent     `json:"events"`
+	Links             []otlpLink      `json:"links"`
+	Status            *otlpSpanStatus `json:"status"`
+}
+
+type otlpKeyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		// Pointers so presence (the oneof field protojson actually
+		// encoded) can be told apart from a present-but-zero value, e.g.
+		// an empty string or 0.0.
+		StringValue *string  `json:"stringValue"`
+		IntValue    *string  `json:"intValue"`
+		BoolValue   *bool    `json:"boolValue"`
+		DoubleValue *float64 `json:"doubleValue"`
+	} `json:"value"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes"`
+}
+
+type otlpLink struct {
+	TraceID string `json:"traceId"`
+	SpanID  string `json:"spanId"`
+}
+
+type otlpSpanStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func parseOTLPJSON(data []byte) ([]*capturedSpan, error) {
+	var req otlpExportRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("failed to parse OTLP JSON replay file: %w", err)
+	}
+	var out []*capturedSpan
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			for _, s := range ss.Spans {
+				out = append(out, otlpToCaptured(s))
+			}
+		}
+	}
+	return out, nil
+}
+
+func otlpToCaptured(s otlpSpan) *capturedSpan {
+	cs := &capturedSpan{
+		TraceID:      decodeOTLPID(s.TraceID),
+		SpanID:       decodeOTLPID(s.SpanID),
+		ParentSpanID: decodeOTLPID(s.ParentSpanID),
+		Name:         s.Name,
+		Kind:         otlpSpanKind(s.Kind),
+		StartTime:    time.Unix(0, parseUnixNano(s.StartTimeUnixNano)),
+		EndTime:      time.Unix(0, parseUnixNano(s.EndTimeUnixNano)),
+	}
+	for _, kv := range s.Attributes {
+		cs.Attributes = append(cs.Attributes, otlpAttribute(kv))
+	}
+	for _, ev := range s.Events {
+		e := capturedEvent{Name: ev.Name, Time: time.Unix(0, parseUnixNano(ev.TimeUnixNano))}
+		for _, kv := range ev.Attributes {
+			e.Attributes = append(e.Attributes, otlpAttribute(kv))
+		}
+		cs.Events = append(cs.Events, e)
+	}
+	for _, l := range s.Links {
+		cs.Links = append(cs.Links, capturedLink{TraceID: decodeOTLPID(l.TraceID), SpanID: decodeOTLPID(l.SpanID)})
+	}
+	if s.Status != nil {
+		cs.StatusCode = otlpStatusCode(s.Status.Code)
+		cs.StatusMsg = s.Status.Message
+	}
+	return cs
+}
+
+// decodeOTLPID accepts either the base64 encoding used by protojson or a
+// plain hex string, since captures in the wild vary by exporter version.
+// A string that is valid hex is returned unchanged; anything else is
+// decoded as base64 when possible.
+func decodeOTLPID(s string) string {
+	if s == "" {
+		return ""
+	}
+	if _, err := hex.DecodeString(s); err == nil {
+		return s
+	}
+	if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+		return hex.EncodeToString(b)
+	}
+	return s
+}
+
+func parseUnixNano(s string) int64 {
+	var n int64
+	_, _ = fmt.Sscanf(s, "%d", &n)
+	return n
+}
+
+func otlpAttribute(kv otlpKeyValue) attribute.KeyValue {
+	switch {
+	case kv.Value.StringValue != nil:
+		return attribute.String(kv.Key, *kv.Value.StringValue)
+	case kv.Value.IntValue != nil:
+		var n int64
+		_, _ = fmt.Sscanf(*kv.Value.IntValue, "%d", &n)
+		return attribute.Int64(kv.Key, n)
+	case kv.Value.DoubleValue != nil:
+		return attribute.Float64(kv.Key, *kv.Value.DoubleValue)
+	case kv.Value.BoolValue != nil:
+		return attribute.Bool(kv.Key, *kv.Value.BoolValue)
+	default:
+		return attribute.String(kv.Key, "")
+	}
+}
+
+func otlpSpanKind(kind int) trace.SpanKind {
+	// Matches the OTLP SpanKind enum values.
+	switch kind {
+	case 2:
+		return trace.SpanKindServer
+	case 3:
+		return trace.SpanKindClient
+	case 4:
+		return trace.SpanKindProducer
+	case 5:
+		return trace.SpanKindConsumer
+	case 1:
+		return trace.SpanKindInternal
+	default:
+		return trace.SpanKindUnspecified
+	}
+}
+
+func otlpStatusCode(code int) codes.Code {
+	switch code {
+	case 1:
+		return codes.Ok
+	case 2:
+		return codes.Error
+	default:
+		return codes.Unset
+	}
+}
+
+// --- Jaeger JSON (GET /api/traces) -----------------------------------------
+
+type jaegerTracesResponse struct {
+	Data []struct {
+		Spans []jaegerSpan `json:"spans"`
+	} `json:"data"`
+}
+
+type jaegerSpan struct {
+	TraceID       string `json:"traceID"`
+	SpanID        string `json:"spanID"`
+	OperationName string `json:"operationName"`
+	References    []struct {
+		RefType string `json:"refType"`
+		TraceID string `json:"traceID"`
+		SpanID  string `json:"spanID"`
+	} `json:"references"`
+	StartTime int64 `json:"startTime"` // microseconds since epoch
+	Duration  int64 `json:"duration"`  // microseconds
+	Tags      []struct {
+		Key   string      `json:"key"`
+		Value interface{} `json:"value"`
+	} `json:"tags"`
+	Logs []struct {
+		Timestamp int64 `json:"timestamp"`
+		Fields    []struct {
+			Key   string      `json:"key"`
+			Value interface{} `json:"value"`
+		} `json:"fields"`
+	} `json:"logs"`
+}
+
+func parseJaegerJSON(data []byte) ([]*capturedSpan, error) {
+	var resp jaegerTracesResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse Jaeger JSON replay file: %w", err)
+	}
+	var out []*capturedSpan
+	for _, trc := range resp.Data {
+		for _, s := range trc.Spans {
+			out = append(out, jaegerToCaptured(s))
+		}
+	}
+	return out, nil
+}
+
+func jaegerToCaptured(s jaegerSpan) *capturedSpan {
+	cs := &capturedSpan{
+		TraceID:   s.TraceID,
+		SpanID:    s.SpanID,
+		Name:      s.OperationName,
+		Kind:      trace.SpanKindUnspecified,
+		StartTime: time.UnixMicro(s.StartTime),
+		EndTime:   time.UnixMicro(s.StartTime + s.Duration),
+	}
+	for _, ref := range s.References {
+		if ref.RefType == "CHILD_OF" {
+			cs.ParentSpanID = ref.SpanID
+		} else {
+			cs.Links = append(cs.Links, capturedLink{TraceID: ref.TraceID, SpanID: ref.SpanID})
+		}
+	}
+	for _, tag := range s.Tags {
+		cs.Attributes = append(cs.Attributes, jaegerTagToAttribute(tag.Key, tag.Value))
+		if tag.Key == "span.kind" {
+			if kind, ok := tag.Value.(string); ok {
+				cs.Kind = spanKindFromString(kind)
+			}
+		}
+	}
+	for _, log := range s.Logs {
+		ev := capturedEvent{Time: time.UnixMicro(log.Timestamp)}
+		for _, f := range log.Fields {
+			if f.Key == "event" {
+				if name, ok := f.Value.(string); ok {
+					ev.Name = name
+				}
+				continue
+			}
+			ev.Attributes = append(ev.Attributes, jaegerTagToAttribute(f.Key, f.Value))
+		}
+		if ev.Name == "" {
+			ev.Name = "log"
+		}
+		cs.Events = append(cs.Events, ev)
+	}
+	return cs
+}
+
+func jaegerTagToAttribute(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	default:
+		return attribute.String(key, fmt.Sprintf("%v", v))
+	}
+}
+
+// --- Zipkin JSON -------------------------------------------------------------
+
+type zipkinSpan struct {
+	TraceID     string            `json:"traceId"`
+	ID          string            `json:"id"`
+	ParentID    string            `json:"parentId"`
+	Name        string            `json:"name"`
+	Kind        string            `json:"kind"`
+	Timestamp   int64             `json:"timestamp"` // microseconds since epoch
+	Duration    int64             `json:"duration"`  // microseconds
+	Tags        map[string]string `json:"tags"`
+	Annotations []struct {
+		Timestamp int64  `json:"timestamp"`
+		Value     string `json:"value"`
+	} `json:"annotations"`
+}
+
+func parseZipkinJSON(data []byte) ([]*capturedSpan, error) {
+	var spans []zipkinSpan
+	if err := json.Unmarshal(data, &spans); err != nil {
+		return nil, fmt.Errorf("failed to parse Zipkin JSON replay file: %w", err)
+	}
+	out := make([]*capturedSpan, 0, len(spans))
+	for _, s := range spans {
+		cs := &capturedSpan{
+			TraceID:      s.TraceID,
+			SpanID:       s.ID,
+			ParentSpanID: s.ParentID,
+			Name:         s.Name,
+			Kind:         zipkinSpanKind(s.Kind),
+			StartTime:    time.UnixMicro(s.Timestamp),
+			EndTime:      time.UnixMicro(s.Timestamp + s.Duration),
+		}
+		for k, v := range s.Tags {
+			cs.Attributes = append(cs.Attributes, attribute.String(k, v))
+		}
+		for _, a := range s.Annotations {
+			cs.Events = append(cs.Events, capturedEvent{Name: a.Value, Time: time.UnixMicro(a.Timestamp)})
+		}
+		out = append(out, cs)
+	}
+	return out, nil
+}
+
+func zipkinSpanKind(kind string) trace.SpanKind {
+	switch kind {
+	case "SERVER":
+		return trace.SpanKindServer
+	case "CLIENT":
+		return trace.SpanKindClient
+	case "PRODUCER":
+		return trace.SpanKindProducer
+	case "CONSUMER":
+		return trace.SpanKindConsumer
+	default:
+		return trace.SpanKindUnspecified
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2024 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracegen
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestParseOTLPJSON(t *testing.T) {
+	data := []byte(`{
+		"resourceSpans": [{
+			"scopeSpans": [{
+				"spans": [{
+					"traceId": "aabbcc",
+					"spanId": "ccddee",
+					"parentSpanId": "ddeeff",
+					"name": "op1",
+					"kind": 2,
+					"startTimeUnixNano": "1000000000",
+					"endTimeUnixNano": "2000000000",
+					"attributes": [{"key": "http.method", "value": {"stringValue": "GET"}}],
+					"status": {"code": 2, "message": "boom"}
+				}]
+			}]
+		}]
+	}`)
+
+	spans, err := parseOTLPJSON(data)
+	if err != nil {
+		t.Fatalf("parseOTLPJSON() error = %v", err)
+	}
+	if len(spans) != 1 {
+		t.Fatalf("len(spans) = %d, want 1", len(spans))
+	}
+	s := spans[0]
+	if s.TraceID != "aabbcc" || s.SpanID != "ccddee" || s.ParentSpanID != "ddeeff" {
+		t.Fatalf("unexpected ids: %+v", s)
+	}
+	if s.Name != "op1" {
+		t.Fatalf("Name = %q, want op1", s.Name)
+	}
+	if s.Kind != trace.SpanKindServer {
+		t.Fatalf("Kind = %v, want SpanKindServer", s.Kind)
+	}
+	if !s.StartTime.Equal(time.Unix(1, 0)) || !s.EndTime.Equal(time.Unix(2, 0)) {
+		t.Fatalf("unexpected times: start=%v end=%v", s.StartTime, s.EndTime)
+	}
+	if s.StatusCode != codes.Error || s.StatusMsg != "boom" {
+		t.Fatalf("unexpected status: code=%v msg=%q", s.StatusCode, s.StatusMsg)
+	}
+}
+
+func TestOTLPAttributePreservesPresentZeroValues(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want attribute.KeyValue
+	}{
+		{
+			name: "empty string value",
+			json: `{"key": "k", "value": {"stringValue": ""}}`,
+			want: attribute.String("k", ""),
+		},
+		{
+			name: "zero double value",
+			json: `{"key": "k", "value": {"doubleValue": 0}}`,
+			want: attribute.Float64("k", 0),
+		},
+		{
+			name: "false bool value",
+			json: `{"key": "k", "value": {"boolValue": false}}`,
+			want: attribute.Bool("k", false),
+		},
+		{
+			name: "zero int value",
+			json: `{"key": "k", "value": {"intValue": "0"}}`,
+			want: attribute.Int64("k", 0),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var kv otlpKeyValue
+			if err := json.Unmarshal([]byte(tc.json), &kv); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if got := otlpAttribute(kv); got != tc.want {
+				t.Fatalf("otlpAttribute() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDecodeOTLPID(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		// Valid hex, so it is returned unchanged without a base64 attempt.
+		{"plain hex", "aabbcc", "aabbcc"},
+		{"base64", "3q2+7w==", "deadbeef"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := decodeOTLPID(tc.in); got != tc.want {
+				t.Fatalf("decodeOTLPID(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseJaegerJSON(t *testing.T) {
+	data := []byte(`{
+		"data": [{
+			"spans": [{
+				"traceID": "t1",
+				"spanID": "s1",
+				"operationName": "op1",
+				"references": [{"refType": "CHILD_OF", "traceID": "t1", "spanID": "parent"}],
+				"startTime": 1000000,
+				"duration": 500000,
+				"tags": [{"key": "span.kind", "value": "client"}],
+				"logs": [{"timestamp": 1200000, "fields": [{"key": "event", "value": "retrying"}]}]
+			}]
+		}]
+	}`)
+
+	spans, err := parseJaegerJSON(data)
+	if err != nil {
+		t.Fatalf("parseJaegerJSON() error = %v", err)
+	}
+	if len(spans) != 1 {
+		t.Fatalf("len(spans) = %d, want 1", len(spans))
+	}
+	s := spans[0]
+	if s.ParentSpanID != "parent" {
+		t.Fatalf("ParentSpanID = %q, want parent", s.ParentSpanID)
+	}
+	if s.Kind != trace.SpanKindClient {
+		t.Fatalf("Kind = %v, want SpanKindClient (from span.kind tag)", s.Kind)
+	}
+	if !s.StartTime.Equal(time.UnixMicro(1000000)) || !s.EndTime.Equal(time.UnixMicro(1500000)) {
+		t.Fatalf("unexpected times: start=%v end=%v", s.StartTime, s.EndTime)
+	}
+	if len(s.Events) != 1 || s.Events[0].Name != "retrying" {
+		t.Fatalf("unexpected events: %+v", s.Events)
+	}
+}
+
+func TestParseZipkinJSON(t *testing.T) {
+	data := []byte(`[{
+		"traceId": "t1",
+		"id": "s1",
+		"parentId": "p1",
+		"name": "op1",
+		"kind": "SERVER",
+		"timestamp": 1000000,
+		"duration": 250000,
+		"tags": {"http.status_code": "200"},
+		"annotations": [{"timestamp": 1100000, "value": "ws"}]
+	}]`)
+
+	spans, err := parseZipkinJSON(data)
+	if err != nil {
+		t.Fatalf("parseZipkinJSON() error = %v", err)
+	}
+	if len(spans) != 1 {
+		t.Fatalf("len(spans) = %d, want 1", len(spans))
+	}
+	s := spans[0]
+	if s.ParentSpanID != "p1" {
+		t.Fatalf("ParentSpanID = %q, want p1", s.ParentSpanID)
+	}
+	if s.Kind != trace.SpanKindServer {
+		t.Fatalf("Kind = %v, want SpanKindServer", s.Kind)
+	}
+	if !s.EndTime.Equal(time.UnixMicro(1250000)) {
+		t.Fatalf("EndTime = %v, want %v", s.EndTime, time.UnixMicro(1250000))
+	}
+	if len(s.Events) != 1 || s.Events[0].Name != "ws" {
+		t.Fatalf("unexpected events: %+v", s.Events)
+	}
+}
+
+func TestParseCapturedSpansDetectsFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"otlp", `{"resourceSpans": []}`},
+		{"jaeger", `{"data": []}`},
+		{"zipkin", `[]`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := parseCapturedSpans([]byte(tc.data)); err != nil {
+				t.Fatalf("parseCapturedSpans(%s) error = %v", tc.name, err)
+			}
+		})
+	}
+
+	if _, err := parseCapturedSpans([]byte(`"not a trace"`)); err == nil {
+		t.Fatal("parseCapturedSpans(unrecognized) = nil error, want error")
+	}
+}
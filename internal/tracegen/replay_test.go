@@ -0,0 +1,224 @@
+// Copyright (c) 2024 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracegen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
+)
+
+func writeReplayFile(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "replay.json")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("failed to write replay file: %v", err)
+	}
+	return path
+}
+
+func TestLoadCapturedTracesGroupsByTraceAndParent(t *testing.T) {
+	// Two traces ("11" and "22") deliberately reuse span ID "aa", as a
+	// low-entropy capture tool might. "11"'s child references parent "aa"
+	// and must resolve to "11"'s own root, not "22"'s same-ID span.
+	path := writeReplayFile(t, `{
+		"resourceSpans": [{
+			"scopeSpans": [{
+				"spans": [
+					{"traceId": "11", "spanId": "aa", "name": "rootA", "kind": 2, "startTimeUnixNano": "0", "endTimeUnixNano": "1000000"},
+					{"traceId": "11", "spanId": "bb", "parentSpanId": "aa", "name": "childA", "kind": 2, "startTimeUnixNano": "1000000", "endTimeUnixNano": "2000000"},
+					{"traceId": "22", "spanId": "aa", "name": "rootB", "kind": 2, "startTimeUnixNano": "0", "endTimeUnixNano": "1000000"}
+				]
+			}]
+		}]
+	}`)
+
+	traces, err := loadCapturedTraces(path)
+	if err != nil {
+		t.Fatalf("loadCapturedTraces() error = %v", err)
+	}
+	if len(traces) != 2 {
+		t.Fatalf("len(traces) = %d, want 2", len(traces))
+	}
+
+	rootsA := traces["11"]
+	if len(rootsA) != 1 || rootsA[0].Name != "rootA" {
+		t.Fatalf("traces[11] roots = %+v, want a single rootA", rootsA)
+	}
+	if len(rootsA[0].children) != 1 || rootsA[0].children[0].Name != "childA" {
+		t.Fatalf("rootA.children = %+v, want a single childA (not misattached to rootB)", rootsA[0].children)
+	}
+
+	rootsB := traces["22"]
+	if len(rootsB) != 1 || rootsB[0].Name != "rootB" {
+		t.Fatalf("traces[22] roots = %+v, want a single rootB", rootsB)
+	}
+	if len(rootsB[0].children) != 0 {
+		t.Fatalf("rootB.children = %+v, want none", rootsB[0].children)
+	}
+}
+
+func TestLoadCapturedTracesRootsSortedByStartTime(t *testing.T) {
+	path := writeReplayFile(t, `{
+		"resourceSpans": [{
+			"scopeSpans": [{
+				"spans": [
+					{"traceId": "t1", "spanId": "late", "name": "late-root", "kind": 2, "startTimeUnixNano": "2000000000", "endTimeUnixNano": "2000000001"},
+					{"traceId": "t1", "spanId": "early", "name": "early-root", "kind": 2, "startTimeUnixNano": "1000000000", "endTimeUnixNano": "1000000001"}
+				]
+			}]
+		}]
+	}`)
+
+	traces, err := loadCapturedTraces(path)
+	if err != nil {
+		t.Fatalf("loadCapturedTraces() error = %v", err)
+	}
+	roots := traces["t1"]
+	if len(roots) != 2 || roots[0].Name != "early-root" || roots[1].Name != "late-root" {
+		t.Fatalf("traces[t1] roots = %+v, want [early-root, late-root] in start-time order", roots)
+	}
+}
+
+// recordedSpan captures what a recordingTracer observed about one emitted
+// span, for assertions that don't need a real exporter.
+type recordedSpan struct {
+	name      string
+	startedAt time.Time
+	endedAt   time.Time
+}
+
+// recordingTracer is a trace.Tracer that records each span's name and the
+// timestamps it was started/ended with, so tests can assert on replay
+// timing and ordering without a real SDK/exporter.
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []recordedSpan
+}
+
+func (rt *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	sp := &recordingSpan{tracer: rt, name: name, startedAt: cfg.Timestamp()}
+	return ctx, sp
+}
+
+func (rt *recordingTracer) record(s recordedSpan) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.spans = append(rt.spans, s)
+}
+
+func (rt *recordingTracer) recordedSpans() []recordedSpan {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return append([]recordedSpan(nil), rt.spans...)
+}
+
+// recordingSpan is a trace.Span that only implements End meaningfully,
+// embedding noop.Span for every other method.
+type recordingSpan struct {
+	noop.Span
+	tracer    *recordingTracer
+	name      string
+	startedAt time.Time
+}
+
+func (s *recordingSpan) End(opts ...trace.SpanEndOption) {
+	cfg := trace.NewSpanEndConfig(opts...)
+	s.tracer.record(recordedSpan{name: s.name, startedAt: s.startedAt, endedAt: cfg.Timestamp()})
+}
+
+func TestReplayTracesScalesTimingBySpeed(t *testing.T) {
+	origin := time.Unix(1000, 0)
+	root := &capturedSpan{
+		TraceID: "t1", SpanID: "root", Name: "root",
+		StartTime: origin, EndTime: origin.Add(100 * time.Millisecond),
+	}
+	child := &capturedSpan{
+		TraceID: "t1", SpanID: "child", ParentSpanID: "root", Name: "child",
+		StartTime: origin.Add(100 * time.Millisecond), EndTime: origin.Add(200 * time.Millisecond),
+	}
+	root.children = []*capturedSpan{child}
+	traces := map[string][]*capturedSpan{"t1": {root}}
+
+	tracer := &recordingTracer{}
+	start := time.Now()
+	replayTraces(context.Background(), tracer, traces, 10, false, zap.NewNop())
+	elapsed := time.Since(start)
+
+	// The capture spans 200ms of wall-clock time; at 10x speed that
+	// should replay in roughly 20ms, certainly well under the original.
+	if elapsed >= 100*time.Millisecond {
+		t.Fatalf("replayTraces() with speed=10 took %v, want well under the unscaled 200ms capture", elapsed)
+	}
+
+	spans := tracer.recordedSpans()
+	if len(spans) != 2 {
+		t.Fatalf("len(recordedSpans) = %d, want 2", len(spans))
+	}
+	byName := map[string]recordedSpan{spans[0].name: spans[0], spans[1].name: spans[1]}
+	r, ok := byName["root"]
+	if !ok {
+		t.Fatalf("recorded spans = %+v, want one named %q", spans, "root")
+	}
+	c, ok := byName["child"]
+	if !ok {
+		t.Fatalf("recorded spans = %+v, want one named %q", spans, "child")
+	}
+	if !c.startedAt.After(r.startedAt) {
+		t.Fatalf("child started at %v, want after root's start %v", c.startedAt, r.startedAt)
+	}
+	if gotGap, wantGap := c.startedAt.Sub(r.startedAt), 10*time.Millisecond; gotGap < wantGap/2 || gotGap > wantGap*2 {
+		t.Fatalf("child started %v after root, want roughly %v (100ms capture gap / speed 10)", gotGap, wantGap)
+	}
+}
+
+func TestReplayTracesStopsPromptlyWhenContextCancelled(t *testing.T) {
+	origin := time.Unix(1000, 0)
+	// A span whose capture gap is much larger than the context timeout
+	// below: without a ctx-aware wait, replayTraces would sleep straight
+	// through the deadline instead of returning when it expires.
+	root := &capturedSpan{
+		TraceID: "t1", SpanID: "root", Name: "root",
+		StartTime: origin, EndTime: origin.Add(10 * time.Second),
+	}
+	traces := map[string][]*capturedSpan{"t1": {root}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		replayTraces(ctx, &recordingTracer{}, traces, 1, false, zap.NewNop())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("replayTraces() did not return within 2s of its context expiring (want it to stop waiting, not sleep through the capture's 10s gap)")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("replayTraces() took %v to return after a 20ms context timeout, want well under the capture's unscaled 10s gap", elapsed)
+	}
+}
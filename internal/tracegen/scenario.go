@@ -0,0 +1,305 @@
+// Copyright (c) 2024 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracegen
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Scenario generates one trace's worth of spans through tracer. Scenarios
+// are registered by name in the package-level registry and selected via
+// Config's `-scenario` flag.
+type Scenario interface {
+	// Name identifies the scenario, as used in the `-scenario` flag.
+	Name() string
+	// Emit generates a single trace.
+	Emit(ctx context.Context, tracer trace.Tracer) error
+}
+
+var (
+	scenarioRegistryMu sync.Mutex
+	scenarioRegistry   = map[string]func(c *Config) Scenario{}
+)
+
+// RegisterScenario adds a scenario constructor to the registry under name,
+// overwriting any existing registration. Built-in scenarios register
+// themselves in this file's init(); callers embedding tracegen can add
+// their own before calling Run.
+func RegisterScenario(name string, factory func(c *Config) Scenario) {
+	scenarioRegistryMu.Lock()
+	defer scenarioRegistryMu.Unlock()
+	scenarioRegistry[name] = factory
+}
+
+func newScenario(name string, c *Config) (Scenario, error) {
+	scenarioRegistryMu.Lock()
+	factory, ok := scenarioRegistry[name]
+	scenarioRegistryMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown scenario %q", name)
+	}
+	return factory(c), nil
+}
+
+func init() {
+	RegisterScenario("default", func(c *Config) Scenario {
+		return &defaultScenario{pause: c.Pause, debug: c.Debug, firehose: c.Firehose}
+	})
+	RegisterScenario("deep_tree", func(c *Config) Scenario { return &deepTreeScenario{depth: c.ScenarioDepth} })
+	RegisterScenario("wide_fanout", func(c *Config) Scenario { return &wideFanoutScenario{fanout: c.ScenarioFanout} })
+	RegisterScenario("error_cascade", func(c *Config) Scenario { return &errorCascadeScenario{depth: c.ScenarioDepth} })
+	RegisterScenario("span_links", func(c *Config) Scenario { return &spanLinksScenario{} })
+	RegisterScenario("long_running", func(c *Config) Scenario { return &longRunningScenario{} })
+}
+
+// weightedScenario is one entry of a parsed `-scenario` flag, e.g.
+// "deep_tree:3".
+type weightedScenario struct {
+	name   string
+	weight int
+}
+
+// parseScenarios parses a comma-separated, optionally weighted scenario
+// list such as "deep_tree:3,wide_fanout:1,error_cascade". A bare name
+// without a ":weight" suffix gets weight 1.
+func parseScenarios(spec string) ([]weightedScenario, error) {
+	var out []weightedScenario
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, weightStr, hasWeight := strings.Cut(part, ":")
+		weight := 1
+		if hasWeight {
+			w, err := strconv.Atoi(weightStr)
+			if err != nil || w <= 0 {
+				return nil, fmt.Errorf("invalid weight in scenario spec %q: weight must be a positive integer", part)
+			}
+			weight = w
+		}
+		out = append(out, weightedScenario{name: name, weight: weight})
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("scenario spec %q does not name any scenarios", spec)
+	}
+	return out, nil
+}
+
+// scenarioPicker selects a weighted-random scenario instance on each call
+// to pick.
+type scenarioPicker struct {
+	scenarios   []Scenario
+	cumWeights  []int
+	totalWeight int
+}
+
+func newScenarioPicker(spec string, c *Config) (*scenarioPicker, error) {
+	weighted, err := parseScenarios(spec)
+	if err != nil {
+		return nil, err
+	}
+	p := &scenarioPicker{}
+	for _, ws := range weighted {
+		s, err := newScenario(ws.name, c)
+		if err != nil {
+			return nil, err
+		}
+		p.scenarios = append(p.scenarios, s)
+		p.totalWeight += ws.weight
+		p.cumWeights = append(p.cumWeights, p.totalWeight)
+	}
+	return p, nil
+}
+
+func (p *scenarioPicker) pick() Scenario {
+	r := rand.Intn(p.totalWeight)
+	for i, cw := range p.cumWeights {
+		if r < cw {
+			return p.scenarios[i]
+		}
+	}
+	return p.scenarios[len(p.scenarios)-1]
+}
+
+// --- built-in scenarios ------------------------------------------------
+
+// defaultScenario reproduces tracegen's original single-service, two-span
+// trace: one root span with a single child, honoring the top-level
+// -pause/-debug/-firehose flags the same way it always has.
+type defaultScenario struct {
+	pause    time.Duration
+	debug    bool
+	firehose bool
+}
+
+func (defaultScenario) Name() string { return "default" }
+
+func (s defaultScenario) Emit(ctx context.Context, tracer trace.Tracer) error {
+	ctx, sp := tracer.Start(ctx, "lets-go", trace.WithAttributes(tags...))
+	markDebugAndFirehose(sp, s.debug, s.firehose)
+
+	_, child := tracer.Start(ctx, "okey-dokey")
+	markDebugAndFirehose(child, s.debug, s.firehose)
+
+	time.Sleep(s.pause)
+	child.End()
+	sp.End()
+	return nil
+}
+
+// deepTreeScenario emits a linear chain of depth spans, each the sole
+// child of the previous one.
+type deepTreeScenario struct {
+	depth int
+}
+
+func (deepTreeScenario) Name() string { return "deep_tree" }
+
+func (s deepTreeScenario) Emit(ctx context.Context, tracer trace.Tracer) error {
+	depth := s.depth
+	if depth <= 0 {
+		depth = 5
+	}
+	for i := 0; i < depth; i++ {
+		var sp trace.Span
+		ctx, sp = tracer.Start(ctx, fmt.Sprintf("step-%d", i))
+		sp.End()
+	}
+	return nil
+}
+
+// wideFanoutScenario emits a root span with fanout children started and
+// ended concurrently.
+type wideFanoutScenario struct {
+	fanout int
+}
+
+func (wideFanoutScenario) Name() string { return "wide_fanout" }
+
+func (s wideFanoutScenario) Emit(ctx context.Context, tracer trace.Tracer) error {
+	fanout := s.fanout
+	if fanout <= 0 {
+		fanout = 5
+	}
+	ctx, root := tracer.Start(ctx, "fan-out")
+	defer root.End()
+
+	var wg sync.WaitGroup
+	for i := 0; i < fanout; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, child := tracer.Start(ctx, fmt.Sprintf("branch-%d", i))
+			child.End()
+		}(i)
+	}
+	wg.Wait()
+	return nil
+}
+
+// errorCascadeScenario emits a linear chain in which the leaf span fails
+// and every ancestor on the way back up also records an Error status (with
+// its own "downstream call failed" description), mimicking a failure propagating through a call chain.
+type errorCascadeScenario struct {
+	depth int
+}
+
+func (errorCascadeScenario) Name() string { return "error_cascade" }
+
+func (s errorCascadeScenario) Emit(ctx context.Context, tracer trace.Tracer) error {
+	depth := s.depth
+	if depth <= 0 {
+		depth = 5
+	}
+	s.recurse(ctx, tracer, depth)
+	return nil
+}
+
+func (s errorCascadeScenario) recurse(ctx context.Context, tracer trace.Tracer, remaining int) {
+	ctx, sp := tracer.Start(ctx, fmt.Sprintf("hop-%d", remaining))
+	defer sp.End()
+
+	if remaining <= 1 {
+		sp.SetStatus(codes.Error, "leaf operation failed")
+		return
+	}
+	s.recurse(ctx, tracer, remaining-1)
+	sp.SetStatus(codes.Error, "downstream call failed")
+}
+
+// spanLinksScenario emits a root span that links to the root of a
+// previously emitted trace, when one is available, to exercise span links
+// across trace IDs.
+type spanLinksScenario struct{}
+
+func (spanLinksScenario) Name() string { return "span_links" }
+
+var (
+	lastRootMu sync.Mutex
+	lastRoot   trace.SpanContext
+)
+
+func (spanLinksScenario) Emit(ctx context.Context, tracer trace.Tracer) error {
+	lastRootMu.Lock()
+	link := lastRoot
+	lastRootMu.Unlock()
+
+	var opts []trace.SpanStartOption
+	if link.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: link}))
+	}
+	ctx, sp := tracer.Start(ctx, "linked-root", opts...)
+	defer sp.End()
+
+	lastRootMu.Lock()
+	lastRoot = sp.SpanContext()
+	lastRootMu.Unlock()
+
+	_, child := tracer.Start(ctx, "linked-child")
+	child.End()
+	return nil
+}
+
+// longRunningScenario emits a single span that stays open for a while,
+// recording periodic events, to exercise long-lived spans in the UI.
+type longRunningScenario struct{}
+
+func (longRunningScenario) Name() string { return "long_running" }
+
+func (longRunningScenario) Emit(ctx context.Context, tracer trace.Tracer) error {
+	const (
+		duration = 5 * time.Second
+		interval = 500 * time.Millisecond
+	)
+	_, sp := tracer.Start(ctx, "long-running-operation")
+	defer sp.End()
+
+	ticks := int(duration / interval)
+	for i := 0; i < ticks; i++ {
+		time.Sleep(interval)
+		sp.AddEvent(fmt.Sprintf("progress-%d", i))
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2024 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracegen
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestParseScenarios(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []weightedScenario
+		wantErr bool
+	}{
+		{
+			name: "bare names default to weight 1",
+			spec: "deep_tree,wide_fanout",
+			want: []weightedScenario{{name: "deep_tree", weight: 1}, {name: "wide_fanout", weight: 1}},
+		},
+		{
+			name: "explicit weights",
+			spec: "deep_tree:3,wide_fanout:1",
+			want: []weightedScenario{{name: "deep_tree", weight: 3}, {name: "wide_fanout", weight: 1}},
+		},
+		{
+			name: "whitespace and blank entries are ignored",
+			spec: " deep_tree:2 , , wide_fanout ",
+			want: []weightedScenario{{name: "deep_tree", weight: 2}, {name: "wide_fanout", weight: 1}},
+		},
+		{
+			name:    "non-positive weight is rejected",
+			spec:    "deep_tree:0",
+			wantErr: true,
+		},
+		{
+			name:    "non-numeric weight is rejected",
+			spec:    "deep_tree:abc",
+			wantErr: true,
+		},
+		{
+			name:    "empty spec is rejected",
+			spec:    "",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseScenarios(tc.spec)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("parseScenarios(%q) = %v, want error", tc.spec, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseScenarios(%q) error = %v", tc.spec, err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("parseScenarios(%q) = %+v, want %+v", tc.spec, got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("parseScenarios(%q)[%d] = %+v, want %+v", tc.spec, i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestScenarioPickerRespectsWeights(t *testing.T) {
+	p, err := newScenarioPicker("deep_tree:3,wide_fanout:1", &Config{ScenarioDepth: 2, ScenarioFanout: 2})
+	if err != nil {
+		t.Fatalf("newScenarioPicker() error = %v", err)
+	}
+
+	counts := map[string]int{}
+	const n = 2000
+	for i := 0; i < n; i++ {
+		counts[p.pick().Name()]++
+	}
+
+	if counts["deep_tree"] == 0 || counts["wide_fanout"] == 0 {
+		t.Fatalf("expected both scenarios to be picked at least once, got %v", counts)
+	}
+	// deep_tree has 3x the weight of wide_fanout, so over enough draws it
+	// should come up clearly more often.
+	if counts["deep_tree"] <= counts["wide_fanout"] {
+		t.Fatalf("expected deep_tree (weight 3) to be picked more often than wide_fanout (weight 1), got %v", counts)
+	}
+}
+
+func TestNewScenarioPickerUnknownScenario(t *testing.T) {
+	if _, err := newScenarioPicker("not_a_real_scenario", &Config{}); err == nil {
+		t.Fatal("newScenarioPicker(unknown) = nil error, want error")
+	}
+}
+
+func TestDefaultScenarioCarriesConfig(t *testing.T) {
+	c := &Config{Pause: 5 * time.Millisecond, Debug: true, Firehose: true}
+	s, err := newScenario("default", c)
+	if err != nil {
+		t.Fatalf("newScenario(default) error = %v", err)
+	}
+	d, ok := s.(*defaultScenario)
+	if !ok {
+		t.Fatalf("newScenario(default) = %T, want *defaultScenario", s)
+	}
+	if d.pause != c.Pause || d.debug != c.Debug || d.firehose != c.Firehose {
+		t.Fatalf("defaultScenario = %+v, want pause=%v debug=%v firehose=%v", d, c.Pause, c.Debug, c.Firehose)
+	}
+}
+
+func TestDefaultScenarioEmitHonorsPause(t *testing.T) {
+	s := &defaultScenario{pause: 10 * time.Millisecond}
+	start := time.Now()
+	if err := s.Emit(context.Background(), noop.NewTracerProvider().Tracer("test")); err != nil {
+		t.Fatalf("Emit() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < s.pause {
+		t.Fatalf("Emit() returned after %v, want at least the configured pause of %v", elapsed, s.pause)
+	}
+}
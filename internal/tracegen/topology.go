@@ -0,0 +1,245 @@
+// Copyright (c) 2024 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Topology describes a directed graph of services used to generate
+// realistic, multi-service traces instead of tracegen's default
+// single-service root+child pattern.
+type Topology struct {
+	// RootService is the service where every generated trace begins.
+	RootService string        `yaml:"rootService" json:"rootService"`
+	Services    []ServiceSpec `yaml:"services" json:"services"`
+
+	byName map[string]*ServiceSpec
+}
+
+// ServiceSpec describes one node in the topology graph.
+type ServiceSpec struct {
+	Name       string          `yaml:"name" json:"name"`
+	Operations []OperationSpec `yaml:"operations" json:"operations"`
+}
+
+// OperationSpec describes one operation a service performs, including the
+// calls it fans out to other services' operations.
+type OperationSpec struct {
+	Name      string      `yaml:"name" json:"name"`
+	Kind      string      `yaml:"kind" json:"kind"` // server|client|producer|consumer|internal
+	Latency   LatencySpec `yaml:"latency" json:"latency"`
+	ErrorRate float64     `yaml:"errorRate" json:"errorRate"`
+	Calls     []CallSpec  `yaml:"calls" json:"calls"`
+}
+
+// LatencySpec describes the distribution tracegen samples a span's
+// duration from. It is a coarse approximation, not a true statistical
+// distribution: durations are drawn uniformly from [Min, Max], with an
+// occasional outlier at P99 to produce a long tail.
+type LatencySpec struct {
+	Min time.Duration `yaml:"min" json:"min"`
+	Max time.Duration `yaml:"max" json:"max"`
+	P99 time.Duration `yaml:"p99" json:"p99"`
+}
+
+// CallSpec describes an outbound call from one operation to another
+// service's operation.
+type CallSpec struct {
+	Service string `yaml:"service" json:"service"`
+	// Operation must name an operation defined on Service.
+	Operation string `yaml:"operation" json:"operation"`
+	// Probability is the chance, in [0,1], that this call is made on any
+	// given trace. A zero value is treated as 1 (always called).
+	Probability float64 `yaml:"probability" json:"probability"`
+}
+
+// LoadTopology reads a topology description from a YAML or JSON file,
+// selecting the decoder by the file's extension.
+func LoadTopology(path string) (*Topology, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read topology file: %w", err)
+	}
+	var t Topology
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		if err := json.Unmarshal(data, &t); err != nil {
+			return nil, fmt.Errorf("failed to parse topology file as JSON: %w", err)
+		}
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &t); err != nil {
+			return nil, fmt.Errorf("failed to parse topology file as YAML: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported topology file extension %q (expected .yaml, .yml or .json)", ext)
+	}
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (t *Topology) validate() error {
+	if t.RootService == "" {
+		return fmt.Errorf("topology: rootService must be set")
+	}
+	t.byName = make(map[string]*ServiceSpec, len(t.Services))
+	for i := range t.Services {
+		s := &t.Services[i]
+		if s.Name == "" {
+			return fmt.Errorf("topology: services[%d] has no name", i)
+		}
+		if _, dup := t.byName[s.Name]; dup {
+			return fmt.Errorf("topology: duplicate service name %q", s.Name)
+		}
+		t.byName[s.Name] = s
+	}
+	root, ok := t.byName[t.RootService]
+	if !ok {
+		return fmt.Errorf("topology: rootService %q is not defined in services", t.RootService)
+	}
+	if len(root.Operations) == 0 {
+		return fmt.Errorf("topology: root service %q must define at least one operation", t.RootService)
+	}
+	for _, s := range t.Services {
+		for _, op := range s.Operations {
+			for _, call := range op.Calls {
+				callee, ok := t.byName[call.Service]
+				if !ok {
+					return fmt.Errorf("topology: service %q operation %q calls undefined service %q", s.Name, op.Name, call.Service)
+				}
+				if findOperation(callee, call.Operation) == nil {
+					return fmt.Errorf("topology: service %q operation %q calls undefined operation %q on service %q", s.Name, op.Name, call.Operation, call.Service)
+				}
+			}
+		}
+	}
+	return t.detectCycles()
+}
+
+// callNode identifies one service/operation pair in the call graph.
+type callNode struct {
+	service   string
+	operation string
+}
+
+func (n callNode) String() string {
+	return n.service + "." + n.operation
+}
+
+// detectCycles walks the call graph rooted at every operation looking for a
+// path that revisits a node it hasn't finished exploring yet (including a
+// trivial self-loop, e.g. service A op1 calling service A op1). Call targets
+// are assumed to already be validated, so every call resolves to a node.
+func (t *Topology) detectCycles() error {
+	const (
+		stateVisiting = iota + 1
+		stateDone
+	)
+	state := make(map[callNode]int)
+	var path []callNode
+
+	var visit func(n callNode) error
+	visit = func(n callNode) error {
+		switch state[n] {
+		case stateDone:
+			return nil
+		case stateVisiting:
+			cyclePath := append(path, n)
+			return fmt.Errorf("topology: cycle detected in call graph: %s", formatCallPath(cyclePath))
+		}
+		state[n] = stateVisiting
+		path = append(path, n)
+
+		op := findOperation(t.byName[n.service], n.operation)
+		for _, call := range op.Calls {
+			if err := visit(callNode{service: call.Service, operation: call.Operation}); err != nil {
+				return err
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[n] = stateDone
+		return nil
+	}
+
+	for _, s := range t.Services {
+		for _, op := range s.Operations {
+			if err := visit(callNode{service: s.Name, operation: op.Name}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func formatCallPath(path []callNode) string {
+	parts := make([]string, len(path))
+	for i, n := range path {
+		parts[i] = n.String()
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// Service looks up a service definition by name. On a Topology that
+// validate has not indexed, it searches Services directly.
+func (t *Topology) Service(name string) (*ServiceSpec, bool) {
+	if t.byName == nil {
+		for i := range t.Services {
+			if t.Services[i].Name == name {
+				return &t.Services[i], true
+			}
+		}
+		return nil, false
+	}
+	s, ok := t.byName[name]
+	return s, ok
+}
+
+// RootOperation returns the root service and the first operation defined
+// on it, which together form the entry point for every generated trace.
+func (t *Topology) RootOperation() (*ServiceSpec, *OperationSpec) {
+	root, _ := t.Service(t.RootService)
+	return root, &root.Operations[0]
+}
+
+func findOperation(svc *ServiceSpec, name string) *OperationSpec {
+	for i := range svc.Operations {
+		if svc.Operations[i].Name == name {
+			return &svc.Operations[i]
+		}
+	}
+	return nil
+}
+
+// pickDuration samples a duration from the latency spec.
+func (l LatencySpec) pickDuration() time.Duration {
+	if l.Max <= l.Min {
+		return l.Min
+	}
+	if l.P99 > l.Max && rand.Float64() < 0.01 {
+		return l.P99
+	}
+	return l.Min + time.Duration(rand.Int63n(int64(l.Max-l.Min)))
+}
@@ -0,0 +1,221 @@
+// Copyright (c) 2024 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracegen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func validTopology() Topology {
+	return Topology{
+		RootService: "frontend",
+		Services: []ServiceSpec{
+			{
+				Name: "frontend",
+				Operations: []OperationSpec{
+					{Name: "handle", Calls: []CallSpec{{Service: "backend", Operation: "query"}}},
+				},
+			},
+			{
+				Name: "backend",
+				Operations: []OperationSpec{
+					{Name: "query"},
+				},
+			},
+		},
+	}
+}
+
+func TestTopologyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(tp *Topology)
+		wantErr string
+	}{
+		{
+			name:   "valid topology",
+			mutate: func(tp *Topology) {},
+		},
+		{
+			name:    "missing root service",
+			mutate:  func(tp *Topology) { tp.RootService = "" },
+			wantErr: "rootService must be set",
+		},
+		{
+			name:    "root service not defined",
+			mutate:  func(tp *Topology) { tp.RootService = "nope" },
+			wantErr: "is not defined in services",
+		},
+		{
+			name: "duplicate service name",
+			mutate: func(tp *Topology) {
+				tp.Services = append(tp.Services, ServiceSpec{Name: "frontend"})
+			},
+			wantErr: "duplicate service name",
+		},
+		{
+			name: "call references undefined service",
+			mutate: func(tp *Topology) {
+				tp.Services[0].Operations[0].Calls[0].Service = "ghost"
+			},
+			wantErr: "calls undefined service",
+		},
+		{
+			name: "call references undefined operation",
+			mutate: func(tp *Topology) {
+				tp.Services[0].Operations[0].Calls[0].Operation = "ghost"
+			},
+			wantErr: "calls undefined operation",
+		},
+		{
+			name: "self-loop is a cycle",
+			mutate: func(tp *Topology) {
+				tp.Services[1].Operations[0].Calls = []CallSpec{{Service: "backend", Operation: "query"}}
+			},
+			wantErr: "cycle detected",
+		},
+		{
+			name: "mutual recursion is a cycle",
+			mutate: func(tp *Topology) {
+				tp.Services[1].Operations[0].Calls = []CallSpec{{Service: "frontend", Operation: "handle"}}
+			},
+			wantErr: "cycle detected",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tp := validTopology()
+			tc.mutate(&tp)
+			err := tp.validate()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validate() = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("validate() = %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func writeTopologyFile(t *testing.T, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("failed to write topology file: %v", err)
+	}
+	return path
+}
+
+const topologyJSON = `{
+	"rootService": "frontend",
+	"services": [
+		{"name": "frontend", "operations": [{"name": "handle", "calls": [{"service": "backend", "operation": "query"}]}]},
+		{"name": "backend", "operations": [{"name": "query"}]}
+	]
+}`
+
+const topologyYAML = `
+rootService: frontend
+services:
+  - name: frontend
+    operations:
+      - name: handle
+        calls:
+          - service: backend
+            operation: query
+  - name: backend
+    operations:
+      - name: query
+`
+
+func TestLoadTopologyParsesJSON(t *testing.T) {
+	path := writeTopologyFile(t, "topo.json", topologyJSON)
+	tp, err := LoadTopology(path)
+	if err != nil {
+		t.Fatalf("LoadTopology() error = %v", err)
+	}
+	if tp.RootService != "frontend" || len(tp.Services) != 2 {
+		t.Fatalf("LoadTopology() = %+v, want rootService=frontend with 2 services", tp)
+	}
+}
+
+func TestLoadTopologyParsesYAML(t *testing.T) {
+	for _, ext := range []string{"topo.yaml", "topo.yml"} {
+		t.Run(ext, func(t *testing.T) {
+			path := writeTopologyFile(t, ext, topologyYAML)
+			tp, err := LoadTopology(path)
+			if err != nil {
+				t.Fatalf("LoadTopology() error = %v", err)
+			}
+			if tp.RootService != "frontend" || len(tp.Services) != 2 {
+				t.Fatalf("LoadTopology() = %+v, want rootService=frontend with 2 services", tp)
+			}
+		})
+	}
+}
+
+func TestLoadTopologyRejectsUnsupportedExtension(t *testing.T) {
+	path := writeTopologyFile(t, "topo.txt", topologyJSON)
+	if _, err := LoadTopology(path); err == nil || !strings.Contains(err.Error(), "unsupported topology file extension") {
+		t.Fatalf("LoadTopology(.txt) error = %v, want \"unsupported topology file extension\"", err)
+	}
+}
+
+func TestLoadTopologyRejectsMissingFile(t *testing.T) {
+	if _, err := LoadTopology(filepath.Join(t.TempDir(), "nope.json")); err == nil {
+		t.Fatal("LoadTopology(missing file) = nil error, want error")
+	}
+}
+
+func TestLoadTopologyRejectsMalformedJSON(t *testing.T) {
+	path := writeTopologyFile(t, "topo.json", "{not valid json")
+	if _, err := LoadTopology(path); err == nil {
+		t.Fatal("LoadTopology(malformed JSON) = nil error, want error")
+	}
+}
+
+func TestLoadTopologyRejectsFailedValidation(t *testing.T) {
+	path := writeTopologyFile(t, "topo.json", `{"services": [{"name": "frontend"}]}`)
+	if _, err := LoadTopology(path); err == nil || !strings.Contains(err.Error(), "rootService must be set") {
+		t.Fatalf("LoadTopology(no rootService) error = %v, want \"rootService must be set\"", err)
+	}
+}
+
+func TestLatencySpecPickDuration(t *testing.T) {
+	l := LatencySpec{}
+	if got := l.pickDuration(); got != 0 {
+		t.Fatalf("zero-value LatencySpec.pickDuration() = %v, want 0", got)
+	}
+
+	l = LatencySpec{Min: 10, Max: 10}
+	if got := l.pickDuration(); got != 10 {
+		t.Fatalf("LatencySpec{Min:10,Max:10}.pickDuration() = %v, want 10 (Max<=Min returns Min)", got)
+	}
+
+	l = LatencySpec{Min: 10, Max: 20}
+	for i := 0; i < 50; i++ {
+		got := l.pickDuration()
+		if got < l.Min || got > l.Max {
+			t.Fatalf("pickDuration() = %v, want within [%v,%v]", got, l.Min, l.Max)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright (c) 2018 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracegen
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+var tags = []attribute.KeyValue{
+	attribute.Key("span.kind").String("server"),
+}
+
+// worker runs a single load-generation goroutine, emitting either traces
+// from the scenarios picked by scenarios, or, when a topology is
+// configured, traces that traverse the configured service graph.
+type worker struct {
+	id        int                               // worker id
+	tracer    trace.Tracer                      // tracer to use for scenarios (non-topology traces)
+	tracerFor func(service string) trace.Tracer // resolves the tracer for a given service name
+	topology  *Topology                         // optional service graph to traverse instead of the default pattern
+	scenarios *scenarioPicker                   // picks a Scenario per trace when topology is nil
+	traces    int                               // how many traces
+	marshal   bool                              // whether to marshal trace context via HTTP headers
+	debug     bool                              // whether to set DEBUG flag on trace
+	firehose  bool                              // whether to set FIREHOSE flag on trace
+	pause     time.Duration                     // how long to pause before finishing trace
+	duration  time.Duration                     // how long to run the test for
+	running   *uint32                           // pointer to shared flag that indicates it's time to stop the test
+	wg        *sync.WaitGroup                   // notify when done
+	logger    *zap.Logger
+
+	limiter  *rate.Limiter // optional: paces trace emission to a target rate
+	inflight chan struct{} // optional: bounds the number of traces being emitted concurrently
+	metrics  *metrics      // optional: records submission latency and outcome counters
+}
+
+func (w worker) simulateTraces() {
+	tracesCount := uint64(0)
+	for atomic.LoadUint32(w.running) == 1 {
+		if w.limiter != nil {
+			if err := w.limiter.Wait(context.Background()); err != nil {
+				break
+			}
+		}
+		if !w.acquireInflight() {
+			if w.metrics != nil {
+				w.metrics.incDropped()
+			}
+			continue
+		}
+
+		start := time.Now()
+		var err error
+		if w.topology != nil {
+			err = w.emitTopologyTrace()
+		} else {
+			err = w.emitScenarioTrace()
+		}
+		w.releaseInflight()
+		if w.metrics != nil {
+			w.metrics.observe(time.Since(start), err)
+		}
+
+		tracesCount++
+		if w.traces != 0 {
+			if tracesCount >= uint64(w.traces) {
+				break
+			}
+		}
+	}
+	w.logger.Info("Worker finished", zap.Uint64("traces", tracesCount))
+	w.wg.Done()
+}
+
+// acquireInflight reserves a slot in the shared inflight semaphore, if one
+// is configured. It never blocks: when the semaphore is full the caller is
+// expected to drop the trace rather than pile up latency.
+func (w worker) acquireInflight() bool {
+	if w.inflight == nil {
+		return true
+	}
+	select {
+	case w.inflight <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w worker) releaseInflight() {
+	if w.inflight != nil {
+		<-w.inflight
+	}
+}
+
+// emitScenarioTrace emits one trace using the scenario chosen by
+// w.scenarios, including the "default" scenario, which reproduces
+// tracegen's original single-service, two-span trace.
+func (w worker) emitScenarioTrace() error {
+	scenario := w.scenarios.pick()
+	if err := scenario.Emit(context.Background(), w.tracer); err != nil {
+		w.logger.Error("scenario emit failed", zap.String("scenario", scenario.Name()), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// emitTopologyTrace starts a trace at the topology's root service and
+// recursively walks its configured outbound calls, producing one span
+// per operation visited.
+func (w worker) emitTopologyTrace() error {
+	svc, op := w.topology.RootOperation()
+	return w.emitServiceCall(context.Background(), svc, op, 0)
+}
+
+// maxCallDepth caps how deep emitServiceCall will recurse into a topology's
+// configured calls. Topology.validate rejects cycles before a topology is
+// ever used, but this remains as a backstop against a cycle introduced by a
+// hand-built Topology that skipped validation, turning what would otherwise
+// be unbounded recursion into a reported, per-trace failure.
+const maxCallDepth = 64
+
+func (w worker) emitServiceCall(ctx context.Context, svc *ServiceSpec, op *OperationSpec, depth int) error {
+	if depth > maxCallDepth {
+		err := fmt.Errorf("topology: call depth exceeded %d at %s.%s (possible cycle)", maxCallDepth, svc.Name, op.Name)
+		w.logger.Error("aborting trace", zap.Error(err))
+		return err
+	}
+
+	tracer := w.tracerFor(svc.Name)
+	ctx, sp := tracer.Start(ctx, op.Name, trace.WithSpanKind(spanKindFromString(op.Kind)))
+	defer sp.End()
+
+	markDebugAndFirehose(sp, w.debug, w.firehose)
+	time.Sleep(op.Latency.pickDuration())
+
+	var firstErr error
+	for _, call := range op.Calls {
+		if call.Probability > 0 && rand.Float64() > call.Probability {
+			continue
+		}
+		calleeSvc, ok := w.topology.Service(call.Service)
+		if !ok {
+			continue
+		}
+		calleeOp := findOperation(calleeSvc, call.Operation)
+		if calleeOp == nil {
+			continue
+		}
+		if err := w.emitServiceCall(ctx, calleeSvc, calleeOp, depth+1); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if op.ErrorRate > 0 && rand.Float64() < op.ErrorRate {
+		sp.SetStatus(codes.Error, "simulated downstream failure")
+	}
+	return firstErr
+}
+
+// markDebugAndFirehose sets the Jaeger-specific debug/firehose span
+// attributes used by both the worker's topology path and the "default"
+// scenario.
+func markDebugAndFirehose(sp trace.Span, debug, firehose bool) {
+	if debug {
+		sp.SetAttributes(attribute.Bool("sampling.priority", true))
+	}
+	if firehose {
+		sp.SetAttributes(attribute.Bool("jaeger.firehose", true))
+	}
+}
+
+func spanKindFromString(kind string) trace.SpanKind {
+	switch kind {
+	case "client":
+		return trace.SpanKindClient
+	case "producer":
+		return trace.SpanKindProducer
+	case "consumer":
+		return trace.SpanKindConsumer
+	case "internal":
+		return trace.SpanKindInternal
+	default:
+		return trace.SpanKindServer
+	}
+}
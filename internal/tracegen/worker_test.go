@@ -0,0 +1,118 @@
+// Copyright (c) 2024 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracegen
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
+)
+
+// recordingProviderFactory is a func(service string) trace.TracerProvider
+// that records every service name it was asked to build a provider for, so
+// tests can assert Run/tracerFor actually vary the provider (and therefore
+// the Resource/service identity) per service, rather than reusing one
+// provider under different tracer names.
+type recordingProviderFactory struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (f *recordingProviderFactory) build(service string) trace.TracerProvider {
+	f.mu.Lock()
+	f.calls = append(f.calls, service)
+	f.mu.Unlock()
+	return noop.NewTracerProvider()
+}
+
+func TestNewCachingTracerForBuildsOneProviderPerService(t *testing.T) {
+	f := &recordingProviderFactory{}
+	tracerFor := newCachingTracerFor(f.build)
+
+	tracerFor("frontend")
+	tracerFor("backend")
+	tracerFor("frontend") // repeat: must reuse the cached provider, not build a second one
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if got, want := f.calls, []string{"frontend", "backend"}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("provider factory calls = %v, want %v (exactly one provider built per distinct service)", got, want)
+	}
+}
+
+func TestEmitTopologyTraceVariesServiceIdentityPerService(t *testing.T) {
+	topo := validTopology()
+	f := &recordingProviderFactory{}
+	w := worker{
+		logger:    zap.NewNop(),
+		topology:  &topo,
+		tracerFor: newCachingTracerFor(f.build),
+	}
+
+	if err := w.emitTopologyTrace(); err != nil {
+		t.Fatalf("emitTopologyTrace() error = %v", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if got, want := f.calls, []string{"frontend", "backend"}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("provider factory calls = %v, want %v (one TracerProvider built per service visited, in call order)", got, want)
+	}
+}
+
+func TestEmitServiceCallAbortsOnExcessiveDepth(t *testing.T) {
+	f := &recordingProviderFactory{}
+	w := worker{
+		logger:    zap.NewNop(),
+		tracerFor: newCachingTracerFor(f.build),
+	}
+	svc := &ServiceSpec{Name: "loopy"}
+	op := &OperationSpec{Name: "op"}
+
+	err := w.emitServiceCall(context.Background(), svc, op, maxCallDepth+1)
+	if err == nil {
+		t.Fatal("emitServiceCall() at depth > maxCallDepth = nil error, want error")
+	}
+}
+
+// TestEmitScenarioTraceHonorsDefaultScenarioPause guards against
+// emitScenarioTrace special-casing the "default" scenario with its own
+// inline copy of the two-span pattern instead of calling scenario.Emit:
+// if it did, this worker's pause (only set on the picked scenario, not on
+// the worker) would never take effect.
+func TestEmitScenarioTraceHonorsDefaultScenarioPause(t *testing.T) {
+	picker, err := newScenarioPicker("default", &Config{Pause: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("newScenarioPicker() error = %v", err)
+	}
+	w := worker{
+		logger:    zap.NewNop(),
+		tracer:    noop.NewTracerProvider().Tracer("test"),
+		scenarios: picker,
+	}
+
+	start := time.Now()
+	if err := w.emitScenarioTrace(); err != nil {
+		t.Fatalf("emitScenarioTrace() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Fatalf("emitScenarioTrace() returned after %v, want at least the scenario's configured pause of 10ms", elapsed)
+	}
+}